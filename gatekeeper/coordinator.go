@@ -0,0 +1,45 @@
+package gatekeeper
+
+import (
+	"fmt"
+	"time"
+)
+
+// Coordinator lets a fleet of vulcand instances share one authoritative
+// counter per (frontend, key) instead of each syncing against a single
+// shared Store independently. Exactly one peer owns a given key (see
+// hashRing), and every peer - including the owner - talks to whichever peer
+// owns a key through this interface.
+type Coordinator interface {
+	// Report sends the tokens used locally since the last report and
+	// returns this instance's new share of whatever quota remains globally.
+	Report(frontend string, key string, used uint64) (share int64, err error)
+
+	// GetRateLimit is called on demand, outside of the regular sync tick,
+	// when a local share has run out early and the instance wants more
+	// before its next scheduled report.
+	GetRateLimit(frontend string, key string) (share int64, err error)
+
+	// PeerCount returns how many peers the coordinator considers active,
+	// including self. Callers size a key's initial fair share off this
+	// rather than the raw configured peer list, since self may have been
+	// added to it implicitly.
+	PeerCount() int
+
+	// Close releases any connections or listeners held by the coordinator.
+	Close() error
+}
+
+// NewCoordinator builds the Coordinator for the given transport. "http" is
+// currently the only one implemented; "grpc" is reserved for a future
+// transport.
+func NewCoordinator(transport string, peers []string, self string, capacity map[string]int64, period time.Duration) (Coordinator, error) {
+	switch transport {
+	case "", "http":
+		return NewHTTPCoordinator(peers, self, capacity, period)
+	case "grpc":
+		return nil, fmt.Errorf("The grpc coordinator transport is not yet implemented")
+	default:
+		return nil, fmt.Errorf("Unknown coordinator transport: %v", transport)
+	}
+}
@@ -2,12 +2,12 @@
 package gatekeeper
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"math/rand"
 	"net/http"
-	"net/url"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -21,18 +21,74 @@ const Type = "gatekeeper"
 // GatekeeperMiddleware struct holds configuration parameters and is used to
 // serialize/deserialize the configuration from storage engines.
 type GatekeeperMiddleware struct {
-	Header   string
-	Frontend string
-	Keys     map[string]GatekeeperKey
+	Header          string
+	Frontend        string
+	Keys            map[string]GatekeeperKey
+	Backend         string   // store backend to share rate limit usage across instances: "memory" or "redis"
+	BackendDSN      string   // connection string for Backend, e.g. a redis host:port
+	Unlimited       []string // api keys that bypass rate limiting entirely
+	SourceExtractor string   // how to key a request's bucket: "header" (default), "ip" or "cidr-prefix"
+	CIDRPrefix      int      // prefix length used when SourceExtractor is "cidr-prefix", e.g. 24
+	DefaultRate     int64    // bucket rate given to sources discovered dynamically under "ip"/"cidr-prefix"
+	MaxDelay        int      // milliseconds to delay a request rather than reject it outright, 0 disables delaying
+
+	// TrustedProxies is how many reverse proxy hops in front of gatekeeper
+	// are trusted to append truthfully to X-Forwarded-For. 0 (the default)
+	// ignores X-Forwarded-For entirely and keys "ip"/"cidr-prefix" buckets
+	// on the connection's own remote address instead, since otherwise any
+	// client could bypass its limit by sending a fabricated header.
+	TrustedProxies int
+
+	SyncInterval         int      // milliseconds between syncs with the Store or Coordinator, defaults to 1000
+	CoordinatorTransport string   // "http" (the default and only implemented transport; "grpc" is rejected by New); used alongside CoordinatorPeers to enable global-counter sync
+	CoordinatorPeers     []string // addresses of every vulcand instance sharing this frontend's limits
+	CoordinatorSelf      string   // this instance's own address, as reachable by the other peers
+
+	// sources holds the per-source buckets used by the "ip" and
+	// "cidr-prefix" extractors. It isn't part of the serialized config, it's
+	// rebuilt by New whenever the middleware is constructed.
+	sources *ttlClientMap
+
+	// clients holds a GatekeeperClient per configured API key in "header"
+	// mode. It isn't part of the serialized config, it's rebuilt by New.
+	clients map[string]*GatekeeperClient
+
+	// store is the sync target used when no coordinator is configured; nil
+	// otherwise. It isn't part of the serialized config.
+	store Store
+
+	// coordinator is set instead of relying on Store when CoordinatorPeers
+	// is configured. It isn't part of the serialized config either.
+	coordinator Coordinator
+
+	// logger receives gatekeeper's debug and error output. It defaults to
+	// logging through the standard library when New isn't given one.
+	logger Logger
+
+	// cancel stops the background sync goroutine and ticker is the fixed
+	// schedule it runs on in store mode (nil under a coordinator, which
+	// jitters its own tick instead). syncDone is closed once the goroutine
+	// has observed cancellation and finished its final sync. None of these
+	// are part of the serialized config.
+	cancel   context.CancelFunc
+	ticker   *time.Ticker
+	syncDone chan struct{}
 }
 
 type GatekeeperKey struct {
-	Rate int64
+	Rate      int64
+	Unlimited bool   // when true this key bypasses rate limiting entirely
+	Algorithm string // "token-bucket" (default), "leaky-bucket" or "sliding-window"
 }
 
 type GatekeeperClient struct {
 	LastSecondUsed uint64
-	Bucket         *ratelimit.Bucket
+	Limiter        Limiter
+
+	// bucket is set only when Limiter is a token-bucket; it's what Store
+	// sync, Coordinator sync and MaxDelay read and mutate directly, since
+	// none of those apply to the other algorithms yet.
+	bucket *ratelimit.Bucket
 }
 
 // Auth middleware handler
@@ -41,34 +97,21 @@ type GatekeeperHandler struct {
 	next   http.Handler
 }
 
-// API rate limiting response
-type GatekeeperClientRateLimit struct {
-	Error     string `json:"error"`
-	Rate      uint64 `json:"rate"`
-	Remaining uint64 `json:"remaining"`
-}
-
 type Configuration struct {
-	Debug bool
 	RateLimitPeriod int
-	GatekeeperProtocol string
-	GatekeeperHost string
-	GatekeeperTimeout int
 }
 
-// A map of gatekeeper clients with their api key as the key and their status as the value
-var clients = make(map[string]*GatekeeperClient)
-
-// Once a second make a best-effort attempt to sync the data in a shared store
-var ticker = time.NewTicker(time.Second * 1)
-
 // Configuration map
 var config = Configuration{
-	Debug: true,
 	RateLimitPeriod: 60, // period for rate limiting allocations in seconds
-	GatekeeperProtocol: "http", // protocol to connect to the gatekeeper rate limiting api
-	GatekeeperHost: "gatekeeper-host.com", // host for the gatekeeper rate limiting api
-	GatekeeperTimeout: 500, // timeout for connecting to the gatekeeper rate limiting api
+}
+
+// jitter returns d plus or minus up to 10%, so peers on the same sync
+// interval don't all report to the coordinator at the exact same instant.
+func jitter(d time.Duration) time.Duration {
+	spread := float64(d) * 0.1
+
+	return d - time.Duration(spread) + time.Duration(rand.Float64()*spread*2)
 }
 
 func GetSpec() *plugin.MiddlewareSpec {
@@ -81,132 +124,431 @@ func GetSpec() *plugin.MiddlewareSpec {
 }
 
 func (handler *GatekeeperHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch handler.config.SourceExtractor {
+	case "ip", "cidr-prefix":
+		handler.serveSource(w, r)
+	default:
+		handler.serveHeader(w, r)
+	}
+}
+
+// serveHeader is the original API-key mode, where the client authenticates
+// with a header whose value must be one of the configured Keys.
+func (handler *GatekeeperHandler) serveHeader(w http.ResponseWriter, r *http.Request) {
 	// Get the clients API key
 	key := r.Header.Get(handler.config.Header)
 
 	// Check for the existence of the API Key in the Keys map
-	if _, ok := handler.config.Keys[key]; ok {
-		// Now attempt to take a token from that clients token bucket
-		taken, remaining := clients[key].Bucket.TakeAvailable(1)
+	meta, ok := handler.config.Keys[key]
 
-		// If were able to take a token then allow the request to continue
-		if taken > 0 {
-			// Increment the LastSecondUsed variable by 1
-			atomic.AddUint64(&clients[key].LastSecondUsed, 1)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, "Unauthorized")
+
+		return
+	}
 
-			// Set some useful headers regarding the rate limits
-			w.Header().Set("X-Rate-Limit-Limit", fmt.Sprintf("%v", handler.config.Keys[key].Rate))
-			w.Header().Set("X-Rate-Limit-Remaining", fmt.Sprintf("%v", remaining))
+	// Unlimited keys are authenticated like any other, they just never have
+	// to take a token from the bucket
+	if meta.Unlimited {
+		w.Header().Set("X-Rate-Limit-Bypass", "true")
 
-			handler.next.ServeHTTP(w, r)
-			// Otherwise inform the client their request is not allowed
-		} else {
-			w.WriteHeader(429)
-			io.WriteString(w, "Too many requests")
+		handler.next.ServeHTTP(w, r)
+
+		return
+	}
+
+	handler.take(w, r, key, meta, handler.config.clients[key])
+}
+
+// serveSource rate limits by client IP or CIDR prefix instead of an API key,
+// creating a bucket for each source the first time it's seen.
+func (handler *GatekeeperHandler) serveSource(w http.ResponseWriter, r *http.Request) {
+	source := extractSource(r, handler.config.SourceExtractor, handler.config.CIDRPrefix, handler.config.TrustedProxies)
+
+	fillInterval := time.Duration(config.RateLimitPeriod) * time.Second
+
+	client, err := handler.config.sources.getOrCreate(source, handler.config.DefaultRate, fillInterval)
+
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, "Too many rate limited sources")
+
+		return
+	}
+
+	handler.take(w, r, source, GatekeeperKey{Rate: handler.config.DefaultRate}, client)
+}
+
+// take dispatches to client's Limiter to decide whether this request may
+// proceed, delaying it up to MaxDelay when the wait to do so would be short
+// rather than rejecting it outright.
+func (handler *GatekeeperHandler) take(w http.ResponseWriter, r *http.Request, key string, meta GatekeeperKey, client *GatekeeperClient) {
+	now := time.Now()
+
+	allowed, remaining, resetAt := client.Limiter.Take(now)
+
+	// A local share can run out before the next scheduled sync; rather than
+	// wait for it, ask the coordinator for more of the global quota now.
+	// Only the token-bucket algorithm participates in coordinator sync.
+	if !allowed && client.bucket != nil && handler.config.coordinator != nil {
+		if share, err := handler.config.coordinator.GetRateLimit(handler.config.Frontend, key); err == nil && share > 0 {
+			client.bucket.SetAvailable(share)
+
+			allowed, remaining, resetAt = client.Limiter.Take(now)
 		}
-		// Otherwise the request is unathorized
+	}
+
+	if !allowed && client.bucket != nil && handler.config.MaxDelay > 0 {
+		maxWait := time.Duration(handler.config.MaxDelay) * time.Millisecond
+
+		if wait, ok := client.bucket.TakeMaxDuration(1, maxWait); ok {
+			time.Sleep(wait)
+
+			allowed = true
+			remaining = uint64(client.bucket.Available())
+		}
+	}
+
+	w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%v", resetAt.Unix()))
+
+	// If we were allowed to proceed then let the request continue
+	if allowed {
+		// Increment the LastSecondUsed variable by 1
+		atomic.AddUint64(&client.LastSecondUsed, 1)
+
+		// Set some useful headers regarding the rate limits
+		w.Header().Set("X-Rate-Limit-Limit", fmt.Sprintf("%v", meta.Rate))
+		w.Header().Set("X-Rate-Limit-Remaining", fmt.Sprintf("%v", remaining))
+
+		handler.next.ServeHTTP(w, r)
+		// Otherwise inform the client their request is not allowed
 	} else {
-		w.WriteHeader(http.StatusUnauthorized)
-		io.WriteString(w, "Unauthorized")
+		w.Header().Set("Retry-After", fmt.Sprintf("%v", int(resetAt.Sub(now).Seconds())))
+		w.WriteHeader(429)
+		io.WriteString(w, "Too many requests")
 	}
 }
 
 // This function is optional but handy, it's used to check input parameters when creating new middlewares
-func New(header string, frontend string, keys map[string]GatekeeperKey) (*GatekeeperMiddleware, error) {
-	if header == "" {
-		return nil, fmt.Errorf("A header must be specified")
+func New(header string, frontend string, keys map[string]GatekeeperKey, backend string, backendDSN string, unlimited []string, sourceExtractor string, cidrPrefix int, trustedProxies int, defaultRate int64, maxDelay int, syncInterval int, coordinatorTransport string, coordinatorPeers []string, coordinatorSelf string, logger Logger) (*GatekeeperMiddleware, error) {
+	if logger == nil {
+		logger = stdLogger{}
 	}
 
-	if len(keys) < 1 {
-		return nil, fmt.Errorf("At least one API key must be specified")
+	if sourceExtractor == "" {
+		sourceExtractor = "header"
 	}
 
-	// Add an entry for each of the clients to the rate limiting system
-	for key, meta := range keys {
-		// Calculate the fill interval for this client
-		fillInterval := time.Duration(config.RateLimitPeriod) * time.Second
+	switch sourceExtractor {
+	case "header":
+		if header == "" {
+			return nil, fmt.Errorf("A header must be specified")
+		}
+
+		if len(keys) < 1 {
+			return nil, fmt.Errorf("At least one API key must be specified")
+		}
+	case "ip", "cidr-prefix":
+		if defaultRate < 1 {
+			return nil, fmt.Errorf("A default rate must be specified for the %v source extractor", sourceExtractor)
+		}
+	default:
+		return nil, fmt.Errorf("Unknown source extractor: %v", sourceExtractor)
+	}
 
-		// Get the capacity and fill amount for the bucket (they are the same in this implementation)
-		capacity := meta.Rate
-		fillAmount := meta.Rate
+	// Mark the keys that should bypass rate limiting entirely
+	for _, key := range unlimited {
+		meta, ok := keys[key]
 
-		clients[key] = &GatekeeperClient{
-			Bucket: ratelimit.NewBucketWithQuantum(fillInterval, capacity, fillAmount),
+		if !ok {
+			return nil, fmt.Errorf("Unlimited key %v is not a configured API key", key)
 		}
+
+		meta.Unlimited = true
+		keys[key] = meta
 	}
 
-	// Every second sync the rate limiting stats with the other gateway server(s)
-	go func() {
-		for range ticker.C {
-			for key, _ := range keys {
-				client := clients[key]
+	// Only the token-bucket algorithm's bucket is synced across instances
+	// (see syncOnce); pairing any other algorithm with a coordinator would
+	// leave every peer silently enforcing the full Rate on its own, with
+	// effective global capacity scaling with the peer count instead of
+	// staying at Rate.
+	if len(coordinatorPeers) > 0 {
+		for key, meta := range keys {
+			if meta.Algorithm != "" && meta.Algorithm != "token-bucket" {
+				return nil, fmt.Errorf("Key %v uses the %v algorithm, which doesn't support CoordinatorPeers", key, meta.Algorithm)
+			}
+		}
+	}
 
-				// The current time
-				now := time.Now()
+	// Calculate the fill interval shared by every client's bucket
+	fillInterval := time.Duration(config.RateLimitPeriod) * time.Second
 
-				// Adjust the buckets stats before we do anything
-				client.Bucket.Adjust(now)
+	if syncInterval < 1 {
+		syncInterval = 1000
+	}
 
-				// Extract a copy of the last second used
-				lastSecondUsed := atomic.LoadUint64(&client.LastSecondUsed)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	middleware := &GatekeeperMiddleware{
+		Header:               header,
+		Frontend:             frontend,
+		Keys:                 keys,
+		Backend:              backend,
+		BackendDSN:           backendDSN,
+		Unlimited:            unlimited,
+		SourceExtractor:      sourceExtractor,
+		CIDRPrefix:           cidrPrefix,
+		TrustedProxies:       trustedProxies,
+		DefaultRate:          defaultRate,
+		MaxDelay:             maxDelay,
+		SyncInterval:         syncInterval,
+		CoordinatorTransport: coordinatorTransport,
+		CoordinatorPeers:     coordinatorPeers,
+		CoordinatorSelf:      coordinatorSelf,
+		clients:              make(map[string]*GatekeeperClient),
+		logger:               logger,
+		cancel:               cancel,
+	}
 
-				// Log the last second used
-				if (config.Debug) {
-					fmt.Println(fmt.Sprintf("[LAST SECOND USED] %v", lastSecondUsed))
-				}
+	if sourceExtractor == "header" {
+		capacity := make(map[string]int64, len(keys))
 
-				// Log the current second used
-				if (config.Debug) {
-					fmt.Println(fmt.Sprintf("[BUCKET USED] %v", client.Bucket.Used()))
-				}
+		for key, meta := range keys {
+			capacity[key] = meta.Rate
+		}
 
-				// Generate the url for the rate limiting server request
-				requestUrl := fmt.Sprintf("%s://%s/v1/frontends/%s/clients/%s", config.GatekeeperProtocol, config.GatekeeperHost, frontend, key)
+		// A coordinator, when configured, replaces the Store as the source
+		// of truth: instead of each instance pushing its usage to a shared
+		// backend, peers elect one authoritative owner per key and that
+		// owner hands out fair shares of what's left on demand
+		if len(coordinatorPeers) > 0 {
+			coordinator, err := NewCoordinator(coordinatorTransport, coordinatorPeers, coordinatorSelf, capacity, fillInterval)
 
-				// Create our http client
-				httpClient := http.Client{
-					Timeout: time.Duration(config.GatekeeperTimeout) * time.Millisecond,
-				}
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+
+			middleware.coordinator = coordinator
+		}
+
+		// The store is what lets multiple vulcand instances share one set of
+		// limits, instead of each instance enforcing its own in isolation.
+		// It's unused when a coordinator is configured.
+		if middleware.coordinator == nil {
+			store, err := NewStore(backend, backendDSN, frontend, keys, fillInterval)
+
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+
+			middleware.store = store
+		}
+
+		// Read the peer count back from the coordinator rather than the
+		// configured list, since self may have been added to the ring
+		// implicitly if coordinatorPeers didn't already include it.
+		activePeers := int64(1)
+
+		if middleware.coordinator != nil {
+			activePeers = int64(middleware.coordinator.PeerCount())
+		}
 
-				// Post to the rate limiting server our Values
-				response, err := httpClient.PostForm(requestUrl, url.Values{"usage": {fmt.Sprintf("%v", lastSecondUsed)}})
-
-				// If no errors occured then try and read in the response body
-				if err == nil {
-					// Read in the JSON response
-					jsonResponse, err := ioutil.ReadAll(response.Body)
-
-					// Again provided no errors occured then try and parse the JSON
-					if err == nil {
-						rateLimitResponse := &GatekeeperClientRateLimit{}
-						err = json.Unmarshal([]byte(jsonResponse), &rateLimitResponse)
-
-						// Finally if on errors occured in the JSON unmarshalling subtract
-						// the global usage minus our own from the bucket
-						if err == nil {
-							// Did an error occur in the API request
-							if rateLimitResponse.Error == "" && response.StatusCode == http.StatusOK {
-								// Set the amount remaining in the bucket
-								client.Bucket.SetAvailable(int64(rateLimitResponse.Remaining))
-							} else {
-								// TODO we should probably log this or do something here
-								fmt.Println(fmt.Sprintf("Status: %v, Response: %s", response.StatusCode, string(jsonResponse)))
-							}
-						}
-					}
+		// Add an entry for each of the clients to the rate limiting system
+		for key, meta := range keys {
+			algorithm := meta.Algorithm
+			rate := meta.Rate
+
+			// Under a coordinator each instance starts out holding only its
+			// fair share of the global rate, not the whole thing, and tops
+			// up from the coordinator as it goes. Only the token-bucket
+			// algorithm participates in distributed sync today.
+			if middleware.coordinator != nil && (algorithm == "" || algorithm == "token-bucket") {
+				rate = meta.Rate / activePeers
+
+				// A key whose Rate is smaller than the peer count would
+				// otherwise divide down to 0, which NewLimiter's underlying
+				// bucket treats as a non-positive capacity and panics on.
+				if rate < 1 {
+					rate = 1
 				}
+			}
+
+			limiter, err := NewLimiter(algorithm, rate, fillInterval)
+
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+
+			client := &GatekeeperClient{Limiter: limiter}
 
-				// Reset the LastSecondUsed counter to 0
-				atomic.StoreUint64(&client.LastSecondUsed, 0)
+			if tokenBucket, ok := limiter.(*tokenBucketLimiter); ok {
+				client.bucket = tokenBucket.bucket
 			}
+
+			middleware.clients[key] = client
 		}
-	}()
 
-	return &GatekeeperMiddleware{
-		Header:   header,
-		Frontend: frontend,
-		Keys:     keys,
-	}, nil
+		middleware.syncDone = make(chan struct{})
+
+		if middleware.coordinator != nil {
+			// Periodically report our usage to the coordinator and adopt
+			// our new fair share of the global rate
+			go middleware.runCoordinatorSync(ctx, time.Duration(syncInterval)*time.Millisecond)
+		} else {
+			// Every syncInterval sync the rate limiting stats with the
+			// shared store
+			middleware.ticker = time.NewTicker(time.Duration(syncInterval) * time.Millisecond)
+
+			go middleware.runStoreSync(ctx)
+		}
+	}
+
+	// Sources discovered dynamically at request time (ip/cidr-prefix modes)
+	// aren't known ahead of time the way API keys are, so their buckets are
+	// created lazily and kept in a bounded, TTL-evicted map instead.
+	if sourceExtractor == "ip" || sourceExtractor == "cidr-prefix" {
+		middleware.sources = newTTLClientMap()
+	}
+
+	return middleware, nil
+}
+
+// runStoreSync reports usage to the shared Store and adopts back whatever
+// quota remains once every tick, until ctx is cancelled, at which point it
+// performs one last sync so nothing used between the final tick and Close
+// is lost.
+func (middleware *GatekeeperMiddleware) runStoreSync(ctx context.Context) {
+	defer close(middleware.syncDone)
+
+	for {
+		select {
+		case <-ctx.Done():
+			middleware.syncOnce()
+			return
+		case <-middleware.ticker.C:
+			middleware.syncOnce()
+		}
+	}
+}
+
+// runCoordinatorSync reports usage to the Coordinator and adopts this
+// instance's new fair share of the global rate, until ctx is cancelled. The
+// tick is jittered so peers on the same interval don't all report in
+// lockstep.
+func (middleware *GatekeeperMiddleware) runCoordinatorSync(ctx context.Context, interval time.Duration) {
+	defer close(middleware.syncDone)
+
+	timer := time.NewTimer(jitter(interval))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			middleware.syncOnce()
+			return
+		case <-timer.C:
+			middleware.syncOnce()
+			timer.Reset(jitter(interval))
+		}
+	}
+}
+
+// syncOnce reports every client's usage since the last sync to whichever of
+// Store or Coordinator is configured, and adopts back its new quota. Only
+// the token-bucket algorithm participates, since it's the only one with a
+// bucket for Store/Coordinator to read and mutate.
+func (middleware *GatekeeperMiddleware) syncOnce() {
+	for key, client := range middleware.clients {
+		// Only token-bucket clients hold a bucket; the other algorithms
+		// aren't synced across instances yet
+		if client.bucket == nil {
+			continue
+		}
+
+		client.bucket.Adjust(time.Now())
+
+		lastSecondUsed := atomic.LoadUint64(&client.LastSecondUsed)
+
+		if middleware.coordinator != nil {
+			share, err := middleware.coordinator.Report(middleware.Frontend, key, lastSecondUsed)
+
+			if err == nil {
+				client.bucket.SetAvailable(share)
+			} else {
+				middleware.logger.Printf("[COORDINATOR SYNC ERROR] key=%v err=%v", key, err)
+			}
+		} else {
+			middleware.logger.Printf("[LAST SECOND USED] %v", lastSecondUsed)
+			middleware.logger.Printf("[BUCKET USED] %v", client.bucket.Used())
+
+			// Report our usage to the store and get back what's left to
+			// share across every instance tracking this key
+			remaining, err := middleware.store.Sync(key, lastSecondUsed)
+
+			if err == nil {
+				client.bucket.SetAvailable(int64(remaining))
+			} else {
+				middleware.logger.Printf("[STORE SYNC ERROR] key=%v err=%v", key, err)
+			}
+		}
+
+		atomic.StoreUint64(&client.LastSecondUsed, 0)
+	}
+}
+
+// Close stops the background sync goroutine, waits for it to finish one
+// last sync so no usage goes unreported, and releases whatever connections
+// the Store or Coordinator hold. It's invoked by vulcand's middleware
+// lifecycle when this middleware is replaced or removed; calling New again
+// without closing the previous instance would otherwise leak its goroutine.
+func (middleware *GatekeeperMiddleware) Close() error {
+	if middleware.cancel != nil {
+		middleware.cancel()
+	}
+
+	if middleware.ticker != nil {
+		middleware.ticker.Stop()
+	}
+
+	if middleware.syncDone != nil {
+		<-middleware.syncDone
+	}
+
+	if middleware.store != nil {
+		if err := middleware.store.Close(); err != nil {
+			return err
+		}
+	}
+
+	if middleware.coordinator != nil {
+		if err := middleware.coordinator.Close(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Stop is an alias for Close, for vulcand versions that invoke the
+// lifecycle hook under that name instead.
+func (middleware *GatekeeperMiddleware) Stop() error {
+	return middleware.Close()
+}
+
+// Coordinator returns the Coordinator configured for this middleware, or
+// nil if CoordinatorPeers wasn't set. The operator wiring up the process
+// that runs this middleware needs it to mount the receiving side of peer
+// requests, e.g. (middleware.Coordinator().(*HTTPCoordinator)).Handler() on
+// whatever address CoordinatorSelf advertises - without it no peer can ever
+// reach this instance when it owns a key, and every owner lookup falls back
+// to treating itself as authoritative.
+func (middleware *GatekeeperMiddleware) Coordinator() Coordinator {
+	return middleware.coordinator
 }
 
 // This function is important, it's called by vulcand to create a new handler from the middleware config
@@ -228,13 +570,35 @@ func (middleware *GatekeeperMiddleware) String() string {
 // fail to register this middleware. The first and the only parameter should be the struct itself, no pointers and
 // other variables. The function should return a middleware interface and error in case if the parameters are wrong.
 func FromOther(middleware GatekeeperMiddleware) (plugin.Middleware, error) {
-	return New(middleware.Header, middleware.Frontend, middleware.Keys)
+	return New(middleware.Header, middleware.Frontend, middleware.Keys, middleware.Backend, middleware.BackendDSN, middleware.Unlimited, middleware.SourceExtractor, middleware.CIDRPrefix, middleware.TrustedProxies, middleware.DefaultRate, middleware.MaxDelay, middleware.SyncInterval, middleware.CoordinatorTransport, middleware.CoordinatorPeers, middleware.CoordinatorSelf, nil)
 }
 
 // FromCli constructs the middleware from the command line
 func FromCli(c *cli.Context) (plugin.Middleware, error) {
-	// TODO, make this work..
-	return New(c.String("header"), c.String("frontend"), make(map[string]GatekeeperKey))
+	// There's no flag to give each key its own rate, so every key named by
+	// --keys gets --default-rate; operators who need per-key rates still
+	// need to go through FromOther's fuller serialized config instead.
+	keys := make(map[string]GatekeeperKey)
+
+	if c.String("keys") != "" {
+		for _, key := range strings.Split(c.String("keys"), ",") {
+			keys[key] = GatekeeperKey{Rate: int64(c.Int("default-rate"))}
+		}
+	}
+
+	unlimited := []string{}
+
+	if c.String("unlimited") != "" {
+		unlimited = strings.Split(c.String("unlimited"), ",")
+	}
+
+	coordinatorPeers := []string{}
+
+	if c.String("coordinator-peers") != "" {
+		coordinatorPeers = strings.Split(c.String("coordinator-peers"), ",")
+	}
+
+	return New(c.String("header"), c.String("frontend"), keys, c.String("backend"), c.String("backend-dsn"), unlimited, c.String("source-extractor"), c.Int("cidr-prefix"), c.Int("trusted-proxies"), int64(c.Int("default-rate")), c.Int("max-delay"), c.Int("sync-interval"), c.String("coordinator-transport"), coordinatorPeers, c.String("coordinator-self"), nil)
 }
 
 // CliFlags will be used by Vulcand construct help and CLI command for the vctl command
@@ -258,5 +622,77 @@ func CliFlags() []cli.Flag {
 			"api keys in a spaceless csv format",
 			"",
 		},
+		cli.StringFlag{
+			"backend, B",
+			"memory",
+			"the store backend to share rate limits across instances: memory or redis",
+			"",
+		},
+		cli.StringFlag{
+			"backend-dsn, D",
+			"",
+			"the connection string for the backend, e.g. a redis host:port",
+			"",
+		},
+		cli.StringFlag{
+			"unlimited, U",
+			"",
+			"api keys that bypass rate limiting, in a spaceless csv format",
+			"",
+		},
+		cli.StringFlag{
+			"source-extractor, S",
+			"header",
+			"what to key rate limit buckets on: header, ip or cidr-prefix",
+			"",
+		},
+		cli.IntFlag{
+			"cidr-prefix, C",
+			24,
+			"the cidr prefix length used by the cidr-prefix source extractor",
+			"",
+		},
+		cli.IntFlag{
+			"trusted-proxies, X",
+			0,
+			"how many trusted reverse proxy hops sit in front of gatekeeper; 0 ignores X-Forwarded-For and uses the remote address",
+			"",
+		},
+		cli.IntFlag{
+			"default-rate, R",
+			0,
+			"the bucket rate given to each api key in keys, or to sources discovered by the ip/cidr-prefix source extractors",
+			"",
+		},
+		cli.IntFlag{
+			"max-delay, M",
+			0,
+			"milliseconds to delay a request rather than reject it outright, 0 disables delaying",
+			"",
+		},
+		cli.IntFlag{
+			"sync-interval, I",
+			1000,
+			"milliseconds between syncs with the store or coordinator",
+			"",
+		},
+		cli.StringFlag{
+			"coordinator-transport, T",
+			"http",
+			"the coordinator transport to use when coordinator-peers is set: http (grpc is not implemented and is rejected)",
+			"",
+		},
+		cli.StringFlag{
+			"coordinator-peers, P",
+			"",
+			"addresses of every peer sharing this frontend's limits, in a spaceless csv format",
+			"",
+		},
+		cli.StringFlag{
+			"coordinator-self, E",
+			"",
+			"this instance's own address, as reachable by the other coordinator-peers",
+			"",
+		},
 	}
 }
@@ -0,0 +1,31 @@
+package gatekeeper
+
+import "testing"
+
+func TestNewRejectsNonTokenBucketAlgorithmUnderCoordinator(t *testing.T) {
+	keys := map[string]GatekeeperKey{
+		"key-a": {Rate: 10, Algorithm: "leaky-bucket"},
+	}
+
+	_, err := New("X-Api-Key", "frontend", keys, "memory", "", nil, "header", 0, 0, 0, 0, 60000, "http", []string{"peer-a:9000"}, "peer-a:9000", nil)
+
+	if err == nil {
+		t.Fatalf("expected an error pairing a non-token-bucket algorithm with CoordinatorPeers")
+	}
+}
+
+func TestNewAllowsTokenBucketUnderCoordinator(t *testing.T) {
+	keys := map[string]GatekeeperKey{
+		"key-a": {Rate: 10},
+	}
+
+	middleware, err := New("X-Api-Key", "frontend", keys, "memory", "", nil, "header", 0, 0, 0, 0, 60000, "http", []string{"peer-a:9000"}, "peer-a:9000", nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := middleware.Close(); err != nil {
+		t.Fatalf("unexpected error closing middleware: %v", err)
+	}
+}
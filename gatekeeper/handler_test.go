@@ -0,0 +1,94 @@
+package gatekeeper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestHandler(t *testing.T, keys map[string]GatekeeperKey, unlimited []string) (*GatekeeperHandler, *bool) {
+	t.Helper()
+
+	middleware, err := New("X-Api-Key", "frontend", keys, "memory", "", unlimited, "header", 0, 0, 0, 0, 60000, "http", nil, "", nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := middleware.Close(); err != nil {
+			t.Fatalf("unexpected error closing middleware: %v", err)
+		}
+	})
+
+	called := false
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	return &GatekeeperHandler{config: *middleware, next: next}, &called
+}
+
+func TestServeHeaderBypassesRateLimitingForUnlimitedKeys(t *testing.T) {
+	keys := map[string]GatekeeperKey{"key-a": {Rate: 1}}
+
+	handler, called := newTestHandler(t, keys, []string{"key-a"})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Api-Key", "key-a")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	if !*called {
+		t.Fatalf("expected the next handler to be called for an unlimited key")
+	}
+
+	if bypass := w.Header().Get("X-Rate-Limit-Bypass"); bypass != "true" {
+		t.Fatalf("expected X-Rate-Limit-Bypass to be set to true, got %q", bypass)
+	}
+}
+
+func TestServeHeaderRejectsUnknownKeys(t *testing.T) {
+	keys := map[string]GatekeeperKey{"key-a": {Rate: 1}}
+
+	handler, called := newTestHandler(t, keys, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Api-Key", "not-a-configured-key")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	if *called {
+		t.Fatalf("expected the next handler not to be called for an unknown key")
+	}
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a 401, got %v", w.Code)
+	}
+}
+
+func TestServeHeaderRateLimitsNormalKeys(t *testing.T) {
+	keys := map[string]GatekeeperKey{"key-a": {Rate: 1}}
+
+	handler, _ := newTestHandler(t, keys, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Api-Key", "key-a")
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, r)
+
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected the first request to be allowed, got %v", first.Code)
+	}
+
+	second := httptest.NewRecorder()
+	handler.ServeHTTP(second, r)
+
+	if second.Code != 429 {
+		t.Fatalf("expected the second request to be rate limited once the bucket is exhausted, got %v", second.Code)
+	}
+}
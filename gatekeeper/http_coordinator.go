@@ -0,0 +1,218 @@
+package gatekeeper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTPCoordinator implements Coordinator over plain HTTP. Every peer runs
+// the same Handler(); a peer that isn't the owner for a key forwards the
+// request on to whichever peer is.
+type HTTPCoordinator struct {
+	self       string
+	ring       *hashRing
+	capacity   map[string]int64
+	period     time.Duration
+	httpClient *http.Client
+
+	mu     sync.Mutex
+	global map[string]*globalCounter
+}
+
+// globalCounter tracks how much of a key's global quota has been used in
+// the current window, for whichever key this instance happens to own.
+type globalCounter struct {
+	used  int64
+	start time.Time
+}
+
+type coordinatorRequest struct {
+	Frontend string `json:"frontend"`
+	Key      string `json:"key"`
+	Used     uint64 `json:"used"`
+}
+
+type coordinatorResponse struct {
+	Error string `json:"error"`
+	Share int64  `json:"share"`
+}
+
+// NewHTTPCoordinator builds a coordinator that elects an owner for each key
+// from peers via consistent hashing. self is this instance's own address
+// and is added to peers if missing.
+func NewHTTPCoordinator(peers []string, self string, capacity map[string]int64, period time.Duration) (*HTTPCoordinator, error) {
+	if self == "" {
+		return nil, fmt.Errorf("A self peer address must be specified for the http coordinator")
+	}
+
+	present := false
+
+	for _, peer := range peers {
+		if peer == self {
+			present = true
+		}
+	}
+
+	if !present {
+		peers = append(peers, self)
+	}
+
+	return &HTTPCoordinator{
+		self:     self,
+		ring:     newHashRing(peers),
+		capacity: capacity,
+		period:   period,
+		httpClient: &http.Client{
+			Timeout: 500 * time.Millisecond,
+		},
+		global: make(map[string]*globalCounter),
+	}, nil
+}
+
+func (coordinator *HTTPCoordinator) Report(frontend string, key string, used uint64) (int64, error) {
+	return coordinator.request(frontend, key, used)
+}
+
+func (coordinator *HTTPCoordinator) GetRateLimit(frontend string, key string) (int64, error) {
+	return coordinator.request(frontend, key, 0)
+}
+
+// PeerCount returns how many peers are on the ring, which always includes
+// self (NewHTTPCoordinator appends it if the caller's peers list omitted
+// it), so it can differ from len(peers) as originally configured.
+func (coordinator *HTTPCoordinator) PeerCount() int {
+	return len(coordinator.ring.peers)
+}
+
+// request routes to the peer that owns (frontend, key), serving it locally
+// if that happens to be us.
+func (coordinator *HTTPCoordinator) request(frontend string, key string, used uint64) (int64, error) {
+	owner := coordinator.ring.owner(frontend + "/" + key)
+
+	if owner == "" || owner == coordinator.self {
+		return coordinator.applyLocally(frontend, key, used)
+	}
+
+	return coordinator.requestRemote(owner, frontend, key, used)
+}
+
+// applyLocally updates the authoritative counter for (frontend, key) and
+// hands back an even slice of what's left across the active peers.
+func (coordinator *HTTPCoordinator) applyLocally(frontend string, key string, used uint64) (int64, error) {
+	coordinator.mu.Lock()
+	defer coordinator.mu.Unlock()
+
+	counterKey := frontend + "/" + key
+	counter, ok := coordinator.global[counterKey]
+	now := time.Now()
+
+	if !ok || now.Sub(counter.start) >= coordinator.period {
+		counter = &globalCounter{start: now}
+		coordinator.global[counterKey] = counter
+	}
+
+	counter.used += int64(used)
+
+	globalRemaining := coordinator.capacity[key] - counter.used
+
+	if globalRemaining < 0 {
+		globalRemaining = 0
+	}
+
+	activePeers := int64(len(coordinator.ring.peers))
+
+	if activePeers < 1 {
+		activePeers = 1
+	}
+
+	share := globalRemaining / activePeers
+
+	return share, nil
+}
+
+func (coordinator *HTTPCoordinator) requestRemote(owner string, frontend string, key string, used uint64) (int64, error) {
+	body, err := json.Marshal(coordinatorRequest{Frontend: frontend, Key: key, Used: used})
+
+	if err != nil {
+		return 0, err
+	}
+
+	response, err := coordinator.httpClient.Post(fmt.Sprintf("http://%s/v1/coordinator/rate-limit", owner), "application/json", bytes.NewReader(body))
+
+	// A peer we can't reach falls back to local-only limiting rather than
+	// blocking every request behind a dead coordinator
+	if err != nil {
+		return coordinator.applyLocally(frontend, key, used)
+	}
+
+	defer response.Body.Close()
+
+	raw, err := ioutil.ReadAll(response.Body)
+
+	if err != nil {
+		return coordinator.applyLocally(frontend, key, used)
+	}
+
+	var decoded coordinatorResponse
+
+	if err := json.Unmarshal(raw, &decoded); err != nil || decoded.Error != "" {
+		return coordinator.applyLocally(frontend, key, used)
+	}
+
+	return decoded.Share, nil
+}
+
+// Handler exposes the endpoint peers call into when they route a
+// (frontend, key) owned by this instance. Operators must mount it on
+// whatever HTTP server listens on the address peers use to reach this
+// instance.
+func (coordinator *HTTPCoordinator) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/v1/coordinator/rate-limit", func(w http.ResponseWriter, r *http.Request) {
+		raw, err := ioutil.ReadAll(r.Body)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var decoded coordinatorRequest
+
+		if err := json.Unmarshal(raw, &decoded); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		share, err := coordinator.applyLocally(decoded.Frontend, decoded.Key, decoded.Used)
+
+		response := coordinatorResponse{Share: share}
+
+		if err != nil {
+			response.Error = err.Error()
+		}
+
+		encoded, err := json.Marshal(response)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(encoded)
+	})
+
+	return mux
+}
+
+func (coordinator *HTTPCoordinator) Close() error {
+	coordinator.httpClient.CloseIdleConnections()
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package gatekeeper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHTTPCoordinatorApplyLocallySharesCapacityAcrossPeers(t *testing.T) {
+	capacity := map[string]int64{"key-a": 100}
+
+	coordinator, err := NewHTTPCoordinator([]string{"peer-a:8080", "peer-b:8080"}, "peer-a:8080", capacity, time.Minute)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	share, err := coordinator.applyLocally("frontend", "key-a", 20)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 100 capacity - 20 used = 80 remaining, split across 2 peers = 40 each.
+	if share != 40 {
+		t.Fatalf("expected a share of 40, got %v", share)
+	}
+}
+
+func TestHTTPCoordinatorApplyLocallyNeverGoesNegative(t *testing.T) {
+	capacity := map[string]int64{"key-a": 10}
+
+	coordinator, err := NewHTTPCoordinator([]string{"peer-a:8080"}, "peer-a:8080", capacity, time.Minute)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	share, err := coordinator.applyLocally("frontend", "key-a", 1000)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if share != 0 {
+		t.Fatalf("expected a share of 0 once usage exceeds capacity, got %v", share)
+	}
+}
+
+func TestHTTPCoordinatorApplyLocallyResetsAfterPeriod(t *testing.T) {
+	capacity := map[string]int64{"key-a": 100}
+
+	coordinator, err := NewHTTPCoordinator([]string{"peer-a:8080"}, "peer-a:8080", capacity, time.Millisecond)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := coordinator.applyLocally("frontend", "key-a", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+
+	share, err := coordinator.applyLocally("frontend", "key-a", 0)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if share != 100 {
+		t.Fatalf("expected the counter to reset to the full capacity once the period elapses, got %v", share)
+	}
+}
+
+func TestHTTPCoordinatorSelfIsAddedToRingIfMissing(t *testing.T) {
+	coordinator, err := NewHTTPCoordinator([]string{"peer-a:8080"}, "peer-b:8080", nil, time.Minute)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if coordinator.PeerCount() != 2 {
+		t.Fatalf("expected self to be added to the peer list, got PeerCount() = %v", coordinator.PeerCount())
+	}
+}
@@ -0,0 +1,53 @@
+package gatekeeper
+
+import (
+	"sync"
+	"time"
+)
+
+// leakyBucketLimiter models requests filling a bucket that leaks at a
+// constant rate; a request is only allowed while there's room left. Unlike
+// the token bucket it never lets a client burst past rate, since the level
+// only ever drains smoothly rather than refilling in one go.
+type leakyBucketLimiter struct {
+	mu   sync.Mutex
+	rate int64
+	leak float64 // units drained per second
+
+	level    float64
+	lastLeak time.Time
+}
+
+func newLeakyBucketLimiter(rate int64, period time.Duration) *leakyBucketLimiter {
+	return &leakyBucketLimiter{
+		rate:     rate,
+		leak:     float64(rate) / period.Seconds(),
+		lastLeak: time.Now(),
+	}
+}
+
+func (limiter *leakyBucketLimiter) Take(now time.Time) (bool, uint64, time.Time) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	elapsed := now.Sub(limiter.lastLeak).Seconds()
+	limiter.level -= elapsed * limiter.leak
+
+	if limiter.level < 0 {
+		limiter.level = 0
+	}
+
+	limiter.lastLeak = now
+
+	resetAt := now.Add(time.Duration(limiter.level/limiter.leak*float64(time.Second)))
+
+	if limiter.level+1 > float64(limiter.rate) {
+		return false, 0, resetAt
+	}
+
+	limiter.level++
+
+	remaining := uint64(float64(limiter.rate) - limiter.level)
+
+	return true, remaining, resetAt
+}
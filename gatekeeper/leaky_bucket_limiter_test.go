@@ -0,0 +1,57 @@
+package gatekeeper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeakyBucketLimiterAllowsBurstUpToRate(t *testing.T) {
+	now := time.Now()
+	limiter := newLeakyBucketLimiter(2, time.Second)
+	limiter.lastLeak = now
+
+	if allowed, remaining, _ := limiter.Take(now); !allowed || remaining != 1 {
+		t.Fatalf("expected first request allowed with 1 remaining, got allowed=%v remaining=%v", allowed, remaining)
+	}
+
+	if allowed, remaining, _ := limiter.Take(now); !allowed || remaining != 0 {
+		t.Fatalf("expected second request allowed with 0 remaining, got allowed=%v remaining=%v", allowed, remaining)
+	}
+
+	if allowed, _, _ := limiter.Take(now); allowed {
+		t.Fatalf("expected a third request at the same instant to be denied")
+	}
+}
+
+func TestLeakyBucketLimiterLeaksOverTime(t *testing.T) {
+	now := time.Now()
+	limiter := newLeakyBucketLimiter(2, time.Second)
+	limiter.lastLeak = now
+
+	limiter.Take(now)
+	limiter.Take(now)
+
+	if allowed, _, _ := limiter.Take(now); allowed {
+		t.Fatalf("expected the bucket to be full")
+	}
+
+	// Leak rate is 2/s, so half a second later there should be room again.
+	later := now.Add(500 * time.Millisecond)
+
+	if allowed, _, _ := limiter.Take(later); !allowed {
+		t.Fatalf("expected room in the bucket after it has had time to leak")
+	}
+}
+
+func TestLeakyBucketLimiterLevelNeverGoesNegative(t *testing.T) {
+	now := time.Now()
+	limiter := newLeakyBucketLimiter(2, time.Second)
+	limiter.lastLeak = now
+
+	// A long idle period should clamp the level at 0 rather than underflow.
+	later := now.Add(time.Hour)
+
+	if allowed, remaining, _ := limiter.Take(later); !allowed || remaining != 1 {
+		t.Fatalf("expected an allowed request with 1 remaining after a long idle period, got allowed=%v remaining=%v", allowed, remaining)
+	}
+}
@@ -0,0 +1,68 @@
+package gatekeeper
+
+import (
+	"fmt"
+	"testing"
+)
+
+// captureLogger is a Logger that records every formatted message it's given,
+// so tests can assert on what gatekeeper logs without depending on stdout.
+type captureLogger struct {
+	messages []string
+}
+
+func (l *captureLogger) Printf(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func TestSyncOnceLogsThroughTheConfiguredLogger(t *testing.T) {
+	logger := &captureLogger{}
+
+	keys := map[string]GatekeeperKey{"key-a": {Rate: 10}}
+
+	middleware, err := New("X-Api-Key", "frontend", keys, "memory", "", nil, "header", 0, 0, 0, 0, 60000, "http", nil, "", logger)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	defer middleware.Close()
+
+	middleware.syncOnce()
+
+	if len(logger.messages) == 0 {
+		t.Fatalf("expected syncOnce to log through the configured Logger")
+	}
+}
+
+func TestCloseIsSafeToCallTwice(t *testing.T) {
+	keys := map[string]GatekeeperKey{"key-a": {Rate: 10}}
+
+	middleware, err := New("X-Api-Key", "frontend", keys, "memory", "", nil, "header", 0, 0, 0, 0, 60000, "http", nil, "", nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := middleware.Close(); err != nil {
+		t.Fatalf("unexpected error on first Close: %v", err)
+	}
+
+	if err := middleware.Close(); err != nil {
+		t.Fatalf("unexpected error on second Close: %v", err)
+	}
+}
+
+func TestStopIsAnAliasForClose(t *testing.T) {
+	keys := map[string]GatekeeperKey{"key-a": {Rate: 10}}
+
+	middleware, err := New("X-Api-Key", "frontend", keys, "memory", "", nil, "header", 0, 0, 0, 0, 60000, "http", nil, "", nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := middleware.Stop(); err != nil {
+		t.Fatalf("unexpected error calling Stop: %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package gatekeeper
+
+import (
+	"fmt"
+	"time"
+)
+
+// Limiter decides, for a single client, whether a request may proceed right
+// now under whatever rate limiting algorithm it implements.
+type Limiter interface {
+	// Take consumes one unit of quota as of now, if available, and reports
+	// whether the request is allowed, how much quota is left afterwards,
+	// and when the window this decision was made against next resets.
+	Take(now time.Time) (allowed bool, remaining uint64, resetAt time.Time)
+}
+
+// NewLimiter builds the Limiter for algorithm ("token-bucket" by default),
+// sized to rate over period.
+func NewLimiter(algorithm string, rate int64, period time.Duration) (Limiter, error) {
+	switch algorithm {
+	case "", "token-bucket":
+		return newTokenBucketLimiter(rate, period), nil
+	case "leaky-bucket":
+		return newLeakyBucketLimiter(rate, period), nil
+	case "sliding-window":
+		return newSlidingWindowLimiter(rate, period), nil
+	default:
+		return nil, fmt.Errorf("Unknown rate limiting algorithm: %v", algorithm)
+	}
+}
@@ -0,0 +1,18 @@
+package gatekeeper
+
+import "log"
+
+// Logger lets operators plug in their own structured logging instead of
+// gatekeeper writing straight to stdout. The standard library's *log.Logger
+// already satisfies it.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// stdLogger is used when New isn't given a Logger; it logs through the
+// standard library's default logger.
+type stdLogger struct{}
+
+func (stdLogger) Printf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
@@ -0,0 +1,68 @@
+package gatekeeper
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore tracks usage in process memory. It is suitable for single-node
+// deployments, or as the default when no shared backend is configured.
+type MemoryStore struct {
+	mu       sync.Mutex
+	period   time.Duration
+	capacity map[string]int64
+	windows  map[string]*memoryWindow
+}
+
+type memoryWindow struct {
+	used  uint64
+	start time.Time
+}
+
+// NewMemoryStore builds a MemoryStore that enforces capacity per key over
+// fixed windows of the given period. A key's window starts at its first Sync
+// and is replaced by a fresh one at the first Sync after period has elapsed.
+func NewMemoryStore(capacity map[string]int64, period time.Duration) *MemoryStore {
+	return &MemoryStore{
+		period:   period,
+		capacity: capacity,
+		windows:  make(map[string]*memoryWindow),
+	}
+}
+
+func (store *MemoryStore) Sync(key string, used uint64) (uint64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now()
+
+	window, ok := store.windows[key]
+
+	if !ok || now.Sub(window.start) >= store.period {
+		window = &memoryWindow{start: now}
+		store.windows[key] = window
+	}
+
+	window.used += used
+
+	remaining := store.capacity[key] - int64(window.used)
+
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return uint64(remaining), nil
+}
+
+func (store *MemoryStore) Reset(key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.windows, key)
+
+	return nil
+}
+
+func (store *MemoryStore) Close() error {
+	return nil
+}
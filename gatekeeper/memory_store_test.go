@@ -0,0 +1,86 @@
+package gatekeeper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreSyncTracksUsageWithinAWindow(t *testing.T) {
+	store := NewMemoryStore(map[string]int64{"key-a": 100}, time.Minute)
+
+	remaining, err := store.Sync("key-a", 20)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if remaining != 80 {
+		t.Fatalf("expected 80 remaining after using 20 of 100, got %v", remaining)
+	}
+
+	remaining, err = store.Sync("key-a", 30)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if remaining != 50 {
+		t.Fatalf("expected 50 remaining after using 50 of 100, got %v", remaining)
+	}
+}
+
+func TestMemoryStoreSyncNeverGoesNegative(t *testing.T) {
+	store := NewMemoryStore(map[string]int64{"key-a": 10}, time.Minute)
+
+	remaining, err := store.Sync("key-a", 1000)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if remaining != 0 {
+		t.Fatalf("expected remaining to floor at 0 once usage exceeds capacity, got %v", remaining)
+	}
+}
+
+func TestMemoryStoreSyncRollsOverToANewWindowAfterPeriod(t *testing.T) {
+	store := NewMemoryStore(map[string]int64{"key-a": 100}, time.Millisecond)
+
+	if _, err := store.Sync("key-a", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+
+	remaining, err := store.Sync("key-a", 0)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if remaining != 100 {
+		t.Fatalf("expected the window to reset to full capacity once the period elapses, got %v", remaining)
+	}
+}
+
+func TestMemoryStoreResetClearsUsage(t *testing.T) {
+	store := NewMemoryStore(map[string]int64{"key-a": 100}, time.Minute)
+
+	if _, err := store.Sync("key-a", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := store.Reset("key-a"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	remaining, err := store.Sync("key-a", 0)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if remaining != 100 {
+		t.Fatalf("expected Reset to restore the full quota, got %v remaining", remaining)
+	}
+}
@@ -0,0 +1,92 @@
+package gatekeeper
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// RedisStore shares usage across every vulcand instance pointed at the same
+// Redis server, using atomic INCRBY/EXPIRE on a per (frontend, key, window)
+// counter so concurrent instances never race on a read-modify-write.
+type RedisStore struct {
+	pool     *redis.Pool
+	frontend string
+	period   time.Duration
+	capacity map[string]int64
+}
+
+// NewRedisStore returns a RedisStore for dsn (a "host:port" address),
+// namespaced to frontend. It doesn't connect up front: connections are dialed
+// from the pool on first use, so an unreachable server only surfaces as an
+// error from Sync or Reset.
+func NewRedisStore(dsn string, frontend string, capacity map[string]int64, period time.Duration) (*RedisStore, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("A redis DSN must be specified for the redis backend")
+	}
+
+	pool := &redis.Pool{
+		MaxIdle:     3,
+		IdleTimeout: 240 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", dsn)
+		},
+	}
+
+	return &RedisStore{
+		pool:     pool,
+		frontend: frontend,
+		period:   period,
+		capacity: capacity,
+	}, nil
+}
+
+func (store *RedisStore) Sync(key string, used uint64) (uint64, error) {
+	conn := store.pool.Get()
+	defer conn.Close()
+
+	windowKey := store.windowKey(key)
+
+	total, err := redis.Int64(conn.Do("INCRBY", windowKey, used))
+	if err != nil {
+		return 0, err
+	}
+
+	// The first writer to a window is responsible for expiring it, so stale
+	// windows are reclaimed by Redis rather than kept around forever.
+	if total == int64(used) {
+		if _, err := conn.Do("EXPIRE", windowKey, int(store.period.Seconds())); err != nil {
+			return 0, err
+		}
+	}
+
+	remaining := store.capacity[key] - total
+
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return uint64(remaining), nil
+}
+
+func (store *RedisStore) Reset(key string) error {
+	conn := store.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", store.windowKey(key))
+
+	return err
+}
+
+func (store *RedisStore) Close() error {
+	return store.pool.Close()
+}
+
+// windowKey namespaces key by frontend and the current fixed window, so
+// separate frontends and separate periods never collide.
+func (store *RedisStore) windowKey(key string) string {
+	window := time.Now().Unix() / int64(store.period.Seconds())
+
+	return fmt.Sprintf("gatekeeper:%s:%s:%d", store.frontend, key, window)
+}
@@ -0,0 +1,53 @@
+package gatekeeper
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNewRedisStoreRequiresDSN(t *testing.T) {
+	if _, err := NewRedisStore("", "frontend", nil, time.Minute); err == nil {
+		t.Fatalf("expected an error when dsn is empty")
+	}
+}
+
+func TestRedisStoreWindowKeyNamespacesByFrontendAndKey(t *testing.T) {
+	store, err := NewRedisStore("localhost:6379", "frontend-a", nil, time.Minute)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "gatekeeper:frontend-a:key-a:" + windowNumber(time.Minute)
+
+	if got := store.windowKey("key-a"); got != expected {
+		t.Fatalf("expected windowKey %q, got %q", expected, got)
+	}
+}
+
+func TestRedisStoreWindowKeyDiffersAcrossFrontends(t *testing.T) {
+	a, err := NewRedisStore("localhost:6379", "frontend-a", nil, time.Minute)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := NewRedisStore("localhost:6379", "frontend-b", nil, time.Minute)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if a.windowKey("key-a") == b.windowKey("key-a") {
+		t.Fatalf("expected separate frontends to namespace the same key differently")
+	}
+}
+
+// windowNumber mirrors RedisStore.windowKey's window arithmetic so the test
+// doesn't hardcode a value that'd need updating every time this runs.
+func windowNumber(period time.Duration) string {
+	window := time.Now().Unix() / int64(period.Seconds())
+
+	return fmt.Sprintf("%d", window)
+}
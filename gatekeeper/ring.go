@@ -0,0 +1,67 @@
+package gatekeeper
+
+import (
+	"fmt"
+	"hash/crc32"
+	"sort"
+)
+
+// ringReplicas is how many points each peer gets on the ring, so load from
+// the (frontend, key) keyspace spreads evenly instead of clumping on
+// whichever peer happens to hash close to a popular key.
+const ringReplicas = 160
+
+// hashRing assigns every (frontend, key) pair to exactly one peer via
+// consistent hashing, so at most one instance is ever authoritative for a
+// given key's global counter.
+type hashRing struct {
+	peers  []string
+	points uint32Slice
+	owners map[uint32]string
+}
+
+type uint32Slice []uint32
+
+func (s uint32Slice) Len() int           { return len(s) }
+func (s uint32Slice) Less(i, j int) bool { return s[i] < s[j] }
+func (s uint32Slice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func newHashRing(peers []string) *hashRing {
+	ring := &hashRing{
+		peers:  peers,
+		owners: make(map[uint32]string, len(peers)*ringReplicas),
+	}
+
+	for _, peer := range peers {
+		for replica := 0; replica < ringReplicas; replica++ {
+			point := crc32.ChecksumIEEE([]byte(fmt.Sprintf("%s-%d", peer, replica)))
+
+			ring.points = append(ring.points, point)
+			ring.owners[point] = peer
+		}
+	}
+
+	sort.Sort(ring.points)
+
+	return ring
+}
+
+// owner returns which peer is authoritative for key, or "" if the ring has
+// no peers.
+func (ring *hashRing) owner(key string) string {
+	if len(ring.points) == 0 {
+		return ""
+	}
+
+	point := crc32.ChecksumIEEE([]byte(key))
+
+	index := sort.Search(len(ring.points), func(i int) bool {
+		return ring.points[i] >= point
+	})
+
+	if index == len(ring.points) {
+		index = 0
+	}
+
+	return ring.owners[ring.points[index]]
+}
@@ -0,0 +1,57 @@
+package gatekeeper
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHashRingOwnerIsSingleAndStable(t *testing.T) {
+	peers := []string{"peer-a:8080", "peer-b:8080", "peer-c:8080"}
+	ring := newHashRing(peers)
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("frontend/key-%d", i)
+
+		owner := ring.owner(key)
+
+		found := false
+
+		for _, peer := range peers {
+			if owner == peer {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			t.Fatalf("owner %q for key %q is not one of the configured peers", owner, key)
+		}
+
+		if second := ring.owner(key); second != owner {
+			t.Fatalf("owner(%q) returned %q then %q on a second call", key, owner, second)
+		}
+	}
+}
+
+func TestHashRingOwnerEmptyWithNoPeers(t *testing.T) {
+	ring := newHashRing(nil)
+
+	if owner := ring.owner("frontend/key"); owner != "" {
+		t.Fatalf("expected no owner with an empty ring, got %q", owner)
+	}
+}
+
+func TestHashRingSpreadsKeysAcrossPeers(t *testing.T) {
+	peers := []string{"peer-a:8080", "peer-b:8080", "peer-c:8080"}
+	ring := newHashRing(peers)
+
+	counts := make(map[string]int, len(peers))
+
+	for i := 0; i < 3000; i++ {
+		counts[ring.owner(fmt.Sprintf("frontend/key-%d", i))]++
+	}
+
+	if len(counts) != len(peers) {
+		t.Fatalf("expected all %d peers to own at least one key, got owners %v", len(peers), counts)
+	}
+}
@@ -0,0 +1,65 @@
+package gatekeeper
+
+import (
+	"sync"
+	"time"
+)
+
+// slidingWindowLimiter keeps two fixed windows of length period - the
+// current one and the one before it - and weights the previous window's
+// count down as the current window progresses. This smooths out the bursts
+// a fixed-window counter allows right at a window boundary, without the
+// bookkeeping of a true sliding log.
+type slidingWindowLimiter struct {
+	mu     sync.Mutex
+	rate   int64
+	period time.Duration
+
+	windowStart time.Time
+	previous    uint64
+	current     uint64
+}
+
+func newSlidingWindowLimiter(rate int64, period time.Duration) *slidingWindowLimiter {
+	return &slidingWindowLimiter{
+		rate:        rate,
+		period:      period,
+		windowStart: time.Now(),
+	}
+}
+
+func (limiter *slidingWindowLimiter) Take(now time.Time) (bool, uint64, time.Time) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	elapsed := now.Sub(limiter.windowStart)
+
+	if elapsed >= limiter.period {
+		windowsElapsed := int64(elapsed / limiter.period)
+
+		if windowsElapsed == 1 {
+			limiter.previous = limiter.current
+		} else {
+			limiter.previous = 0
+		}
+
+		limiter.current = 0
+		limiter.windowStart = limiter.windowStart.Add(time.Duration(windowsElapsed) * limiter.period)
+		elapsed = now.Sub(limiter.windowStart)
+	}
+
+	weight := 1 - (float64(elapsed) / float64(limiter.period))
+	weighted := float64(limiter.previous)*weight + float64(limiter.current)
+
+	resetAt := limiter.windowStart.Add(limiter.period)
+
+	if weighted >= float64(limiter.rate) {
+		return false, 0, resetAt
+	}
+
+	limiter.current++
+
+	remaining := uint64(float64(limiter.rate) - weighted - 1)
+
+	return true, remaining, resetAt
+}
@@ -0,0 +1,74 @@
+package gatekeeper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlidingWindowLimiterAllowsUpToRatePerWindow(t *testing.T) {
+	limiter := newSlidingWindowLimiter(3, time.Minute)
+	now := time.Now()
+	limiter.windowStart = now
+
+	for i := 0; i < 3; i++ {
+		allowed, _, _ := limiter.Take(now)
+
+		if !allowed {
+			t.Fatalf("request %d should have been allowed", i)
+		}
+	}
+
+	if allowed, _, _ := limiter.Take(now); allowed {
+		t.Fatalf("request beyond rate should have been denied")
+	}
+}
+
+func TestSlidingWindowLimiterWeighsThePreviousWindowDown(t *testing.T) {
+	period := time.Minute
+	limiter := newSlidingWindowLimiter(2, period)
+
+	start := time.Now()
+	limiter.windowStart = start
+
+	// Fill the first window completely.
+	if allowed, _, _ := limiter.Take(start); !allowed {
+		t.Fatalf("expected first request to be allowed")
+	}
+
+	if allowed, _, _ := limiter.Take(start); !allowed {
+		t.Fatalf("expected second request to be allowed")
+	}
+
+	// Halfway into the next window, the previous window's count is weighted
+	// at roughly 50%, so one of its two requests still counts against the
+	// limit and only one more request should be allowed.
+	halfway := start.Add(period + period/2)
+
+	if allowed, _, _ := limiter.Take(halfway); !allowed {
+		t.Fatalf("expected a request halfway through the next window to be allowed")
+	}
+
+	if allowed, _, _ := limiter.Take(halfway); allowed {
+		t.Fatalf("expected the weighted previous window to still count against the limit")
+	}
+}
+
+func TestSlidingWindowLimiterForgetsTwoWindowsAgo(t *testing.T) {
+	period := time.Minute
+	limiter := newSlidingWindowLimiter(1, period)
+
+	start := time.Now()
+	limiter.windowStart = start
+
+	if allowed, _, _ := limiter.Take(start); !allowed {
+		t.Fatalf("expected first request to be allowed")
+	}
+
+	// Three windows later the previous window is no longer the one that was
+	// filled, so its weighted count should be zero.
+	later := start.Add(3 * period)
+
+	if allowed, _, _ := limiter.Take(later); !allowed {
+		t.Fatalf("expected a request three windows later to be allowed")
+	}
+}
@@ -0,0 +1,67 @@
+package gatekeeper
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// clientIP extracts the client's address, trusting X-Forwarded-For only as
+// far as trustedProxies allows. A client can put anything in that header,
+// but each trusted proxy in the chain appends the address it actually saw,
+// so the entry trustedProxies-from-the-right is the last one no client
+// could have forged. trustedProxies of 0 ignores the header entirely and
+// uses the connection's own remote address.
+func clientIP(r *http.Request, trustedProxies int) string {
+	if trustedProxies > 0 {
+		if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+			hops := strings.Split(forwardedFor, ",")
+
+			if index := len(hops) - trustedProxies; index >= 0 {
+				return strings.TrimSpace(hops[index])
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// cidrPrefix masks ip down to the given prefix length, so e.g. every client
+// in a /24 shares one bucket. ip is returned unchanged if it can't be parsed
+// or prefix doesn't narrow it.
+func cidrPrefix(ip string, prefix int) string {
+	parsed := net.ParseIP(ip)
+
+	if parsed == nil {
+		return ip
+	}
+
+	bits := 32
+
+	if parsed.To4() == nil {
+		bits = 128
+	}
+
+	if prefix <= 0 || prefix >= bits {
+		return ip
+	}
+
+	return parsed.Mask(net.CIDRMask(prefix, bits)).String()
+}
+
+// extractSource returns the key a request's rate limit bucket is keyed on,
+// according to the middleware's configured SourceExtractor.
+func extractSource(r *http.Request, extractor string, prefix int, trustedProxies int) string {
+	switch extractor {
+	case "cidr-prefix":
+		return cidrPrefix(clientIP(r, trustedProxies), prefix)
+	default: // "ip"
+		return clientIP(r, trustedProxies)
+	}
+}
@@ -0,0 +1,40 @@
+package gatekeeper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPIgnoresForwardedForByDefault(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "203.0.113.9:1234"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+
+	if ip := clientIP(r, 0); ip != "203.0.113.9" {
+		t.Fatalf("expected the remote address to be used when trustedProxies is 0, got %q", ip)
+	}
+}
+
+func TestClientIPTrustsTheLastHopPerTrustedProxy(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "203.0.113.9:1234"
+	r.Header.Set("X-Forwarded-For", "client-forged, 10.0.0.1, 10.0.0.2")
+
+	// With two trusted proxies, only the entry appended by the first one
+	// (second from the right) is trustworthy; the client-forged leftmost
+	// entry and anything past the trusted chain is ignored.
+	if ip := clientIP(r, 2); ip != "10.0.0.1" {
+		t.Fatalf("expected the second-from-right hop to be trusted, got %q", ip)
+	}
+}
+
+func TestClientIPFallsBackWhenFewerHopsThanTrusted(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "203.0.113.9:1234"
+	r.Header.Set("X-Forwarded-For", "10.0.0.2")
+
+	if ip := clientIP(r, 2); ip != "203.0.113.9" {
+		t.Fatalf("expected a fallback to the remote address when X-Forwarded-For has fewer hops than trusted, got %q", ip)
+	}
+}
@@ -0,0 +1,45 @@
+package gatekeeper
+
+import (
+	"fmt"
+	"time"
+)
+
+// Store is implemented by the backends that gatekeeper uses to share rate
+// limit usage across multiple vulcand instances. Each instance reports the
+// amount of a key's quota it has used locally since the last sync, and gets
+// back the quota remaining across the whole cluster for that key.
+type Store interface {
+	// Sync reports that used tokens have been consumed for key since the
+	// last call and returns the number of tokens remaining in the current
+	// window, shared across every instance using this store.
+	Sync(key string, used uint64) (remaining uint64, err error)
+
+	// Reset clears any usage recorded for key, restoring its full quota.
+	Reset(key string) error
+
+	// Close releases any resources (connections, goroutines) held by the
+	// store. It is called when the owning middleware is torn down.
+	Close() error
+}
+
+// NewStore builds the Store configured for backend, sized according to the
+// rate of each key in keys. frontend namespaces the keys so the same API key
+// used on two frontends is tracked independently, and period is the length
+// of the rate limiting window.
+func NewStore(backend string, dsn string, frontend string, keys map[string]GatekeeperKey, period time.Duration) (Store, error) {
+	capacity := make(map[string]int64, len(keys))
+
+	for key, meta := range keys {
+		capacity[key] = meta.Rate
+	}
+
+	switch backend {
+	case "", "memory":
+		return NewMemoryStore(capacity, period), nil
+	case "redis":
+		return NewRedisStore(dsn, frontend, capacity, period)
+	default:
+		return nil, fmt.Errorf("Unknown gatekeeper backend: %v", backend)
+	}
+}
@@ -0,0 +1,42 @@
+package gatekeeper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewStoreDefaultsToMemory(t *testing.T) {
+	store, err := NewStore("", "", "frontend", nil, time.Minute)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := store.(*MemoryStore); !ok {
+		t.Fatalf("expected an empty backend to default to *MemoryStore, got %T", store)
+	}
+}
+
+func TestNewStoreBuildsRedisStore(t *testing.T) {
+	store, err := NewStore("redis", "localhost:6379", "frontend", nil, time.Minute)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := store.(*RedisStore); !ok {
+		t.Fatalf("expected the redis backend to build a *RedisStore, got %T", store)
+	}
+}
+
+func TestNewStoreRejectsUnknownBackend(t *testing.T) {
+	if _, err := NewStore("memcached", "", "frontend", nil, time.Minute); err == nil {
+		t.Fatalf("expected an error for an unknown backend")
+	}
+}
+
+func TestNewStoreRejectsRedisWithoutDSN(t *testing.T) {
+	if _, err := NewStore("redis", "", "frontend", nil, time.Minute); err == nil {
+		t.Fatalf("expected an error when the redis backend has no DSN")
+	}
+}
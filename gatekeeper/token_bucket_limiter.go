@@ -0,0 +1,37 @@
+package gatekeeper
+
+import (
+	"time"
+
+	"github.com/miniclip/ratelimit"
+)
+
+// tokenBucketLimiter is the original algorithm: a bucket refills at a
+// constant rate and each request takes one token, allowing bursts up to
+// rate's worth of requests at once. It's the only algorithm that
+// participates in Store/Coordinator sync and MaxDelay, via the underlying
+// bucket held alongside it on GatekeeperClient.
+type tokenBucketLimiter struct {
+	rate   int64
+	period time.Duration
+	bucket *ratelimit.Bucket
+}
+
+func newTokenBucketLimiter(rate int64, period time.Duration) *tokenBucketLimiter {
+	return &tokenBucketLimiter{
+		rate:   rate,
+		period: period,
+		bucket: ratelimit.NewBucketWithQuantum(period, rate, rate),
+	}
+}
+
+// Take reports resetAt as now plus period: the latest the bucket's next
+// refill can happen, not the exact refill time, which the bucket doesn't
+// expose.
+func (limiter *tokenBucketLimiter) Take(now time.Time) (bool, uint64, time.Time) {
+	limiter.bucket.Adjust(now)
+
+	taken, remaining := limiter.bucket.TakeAvailable(1)
+
+	return taken > 0, remaining, now.Add(limiter.period)
+}
@@ -0,0 +1,96 @@
+package gatekeeper
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxSourceEntries bounds how many distinct sources (IPs or CIDR prefixes)
+// can hold a bucket at once, since that set is unbounded and not known up
+// front the way API keys are.
+const maxSourceEntries = 65536
+
+// sourceIdleTTL is how long a source can go unused before its bucket is
+// reclaimed.
+const sourceIdleTTL = 5 * time.Minute
+
+// evictInterval bounds how often getOrCreate runs a full idle scan off the
+// back of a cache miss. A steady stream of never-before-seen sources misses
+// on every request, so scanning every single time would make every lookup
+// pay an O(current-size) cost while holding m.mu, serializing every request
+// behind it.
+const evictInterval = 30 * time.Second
+
+// ttlClientMap lazily creates a GatekeeperClient per source key, capped at
+// maxSourceEntries and evicting entries idle for longer than sourceIdleTTL.
+type ttlClientMap struct {
+	mu        sync.Mutex
+	entries   map[string]*ttlClientEntry
+	lastEvict time.Time
+}
+
+type ttlClientEntry struct {
+	client   *GatekeeperClient
+	lastUsed time.Time
+}
+
+func newTTLClientMap() *ttlClientMap {
+	return &ttlClientMap{
+		entries: make(map[string]*ttlClientEntry),
+	}
+}
+
+// getOrCreate returns the existing bucket for key, or creates one sized for
+// rate/period if this is the first time key has been seen.
+func (m *ttlClientMap) getOrCreate(key string, rate int64, period time.Duration) (*GatekeeperClient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry, ok := m.entries[key]; ok {
+		entry.lastUsed = time.Now()
+
+		return entry.client, nil
+	}
+
+	// At capacity it's always worth reclaiming whatever's gone idle before
+	// giving up; otherwise a scan only runs once every evictInterval, so a
+	// steady stream of fresh sources isn't scanning the whole map on every
+	// single miss.
+	if len(m.entries) >= maxSourceEntries || time.Since(m.lastEvict) >= evictInterval {
+		m.evict()
+	}
+
+	if len(m.entries) >= maxSourceEntries {
+		return nil, fmt.Errorf("Too many rate limited sources, at capacity %v", maxSourceEntries)
+	}
+
+	// Sources discovered dynamically always rate limit on the token-bucket
+	// algorithm; there's no per-source GatekeeperKey to pick another one.
+	limiter := newTokenBucketLimiter(rate, period)
+
+	client := &GatekeeperClient{
+		Limiter: limiter,
+		bucket:  limiter.bucket,
+	}
+
+	m.entries[key] = &ttlClientEntry{
+		client:   client,
+		lastUsed: time.Now(),
+	}
+
+	return client, nil
+}
+
+// evict removes entries that have been idle for longer than sourceIdleTTL.
+// Callers must hold m.mu.
+func (m *ttlClientMap) evict() {
+	m.lastEvict = time.Now()
+	cutoff := m.lastEvict.Add(-sourceIdleTTL)
+
+	for key, entry := range m.entries {
+		if entry.lastUsed.Before(cutoff) {
+			delete(m.entries, key)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package gatekeeper
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTTLClientMapGetOrCreateReusesExistingEntry(t *testing.T) {
+	m := newTTLClientMap()
+
+	first, err := m.getOrCreate("a", 10, time.Second)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	second, err := m.getOrCreate("a", 10, time.Second)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if first != second {
+		t.Fatalf("expected the same client to be returned for the same key")
+	}
+}
+
+func TestTTLClientMapEvictsIdleEntries(t *testing.T) {
+	m := newTTLClientMap()
+
+	if _, err := m.getOrCreate("a", 10, time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Back-date the entry past sourceIdleTTL, and force the next getOrCreate
+	// to scan (it's otherwise throttled to once per evictInterval) so it
+	// evicts "a" before creating "b".
+	m.entries["a"].lastUsed = time.Now().Add(-sourceIdleTTL - time.Second)
+	m.lastEvict = time.Time{}
+
+	if _, err := m.getOrCreate("b", 10, time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := m.entries["a"]; ok {
+		t.Fatalf("expected idle entry \"a\" to have been evicted")
+	}
+
+	if _, ok := m.entries["b"]; !ok {
+		t.Fatalf("expected entry \"b\" to be present")
+	}
+}
+
+func TestTTLClientMapEnforcesCapacity(t *testing.T) {
+	m := newTTLClientMap()
+
+	for i := 0; i < maxSourceEntries; i++ {
+		if _, err := m.getOrCreate(fmt.Sprintf("key-%d", i), 10, time.Second); err != nil {
+			t.Fatalf("unexpected error filling map: %v", err)
+		}
+	}
+
+	if _, err := m.getOrCreate("one-too-many", 10, time.Second); err == nil {
+		t.Fatalf("expected an error once the map is at capacity")
+	}
+}